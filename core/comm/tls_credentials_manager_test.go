@@ -0,0 +1,238 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/assert"
+)
+
+// generateSelfSignedPEM creates a throwaway self-signed certificate/key pair
+// for use as test fixtures; it is not suitable for production use. When
+// commonName is an IP address it is also set as the certificate's IP SAN,
+// since hostname verification checks SANs rather than the common name.
+func generateSelfSignedPEM(t *testing.T, commonName string) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(commonName); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	assert.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	assert.NoError(t, err)
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM
+}
+
+func x509ParseLeaf(cert tls.Certificate) (*x509.Certificate, error) {
+	return x509.ParseCertificate(cert.Certificate[0])
+}
+
+// writeKeyPair generates a self-signed certificate/key pair for commonName
+// and writes them to certPath/keyPath, returning the generated tls.Certificate.
+func writeKeyPair(t *testing.T, certPath, keyPath, commonName string) tls.Certificate {
+	t.Helper()
+	certPEM, keyPEM := generateSelfSignedPEM(t, commonName)
+	assert.NoError(t, ioutil.WriteFile(certPath, certPEM, 0o600))
+	assert.NoError(t, ioutil.WriteFile(keyPath, keyPEM, 0o600))
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	assert.NoError(t, err)
+	return cert
+}
+
+func TestTLSCredentialsManagerReloadsCertOnChange(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tempDir, err := ioutil.TempDir("", "tls-reload")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(tempDir)
+
+	certPath := filepath.Join(tempDir, "server.crt")
+	keyPath := filepath.Join(tempDir, "server.key")
+	writeKeyPair(t, certPath, keyPath, "old-cert")
+
+	mgr, err := NewTLSCredentialsManager(TLSConfig{
+		Enabled:        true,
+		CertFile:       certPath,
+		KeyFile:        keyPath,
+		ReloadInterval: 50 * time.Millisecond,
+		MinVersion:     tls.VersionTLS12,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	defer mgr.Stop()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go func() {
+		for {
+			rawConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go mgr.ServerHandshake(rawConn)
+		}
+	}()
+
+	dial := func() error {
+		conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		return conn.Handshake()
+	}
+
+	g.Eventually(dial).Should(Succeed())
+
+	writeKeyPair(t, certPath, keyPath, "new-cert")
+	g.Eventually(func() string {
+		mgr.mutex.RLock()
+		defer mgr.mutex.RUnlock()
+		if len(mgr.cert.Certificate) == 0 {
+			return ""
+		}
+		leaf, err := x509ParseLeaf(mgr.cert)
+		if err != nil {
+			return ""
+		}
+		return leaf.Subject.CommonName
+	}, 2*time.Second).Should(Equal("new-cert"))
+
+	g.Eventually(dial).Should(Succeed())
+}
+
+func TestTLSCredentialsManagerDisabledIsPassthrough(t *testing.T) {
+	mgr, err := NewTLSCredentialsManager(TLSConfig{Enabled: false})
+	assert.NoError(t, err)
+	defer mgr.Stop()
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	conn, _, err := mgr.ServerHandshake(server)
+	assert.NoError(t, err)
+	assert.Equal(t, server, conn)
+}
+
+// TestTLSCredentialsManagerClientHandshakeVerifiesAgainstRootCertFiles covers
+// the loopback dial a TLS-enabled peer's own gateway makes against its own
+// gRPC listener: with peer.tls.rootcert.files pointed at the serving
+// certificate, ClientHandshake must trust it; without it, verification must
+// fail rather than silently skipping verification.
+func TestTLSCredentialsManagerClientHandshakeVerifiesAgainstRootCertFiles(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tempDir, err := ioutil.TempDir("", "tls-client-roots")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(tempDir)
+
+	certPath := filepath.Join(tempDir, "server.crt")
+	keyPath := filepath.Join(tempDir, "server.key")
+	writeKeyPair(t, certPath, keyPath, "127.0.0.1")
+
+	serverMgr, err := NewTLSCredentialsManager(TLSConfig{
+		Enabled:    true,
+		CertFile:   certPath,
+		KeyFile:    keyPath,
+		MinVersion: tls.VersionTLS12,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	defer serverMgr.Stop()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go func() {
+		for {
+			rawConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serverMgr.ServerHandshake(rawConn)
+		}
+	}()
+
+	dialWith := func(rootCertFiles []string) error {
+		clientMgr, err := NewTLSCredentialsManager(TLSConfig{
+			Enabled:       true,
+			CertFile:      certPath,
+			KeyFile:       keyPath,
+			RootCertFiles: rootCertFiles,
+			MinVersion:    tls.VersionTLS12,
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		defer clientMgr.Stop()
+
+		rawConn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			return err
+		}
+		_, _, err = clientMgr.ClientHandshake(context.Background(), "127.0.0.1", rawConn)
+		return err
+	}
+
+	g.Eventually(func() error { return dialWith([]string{certPath}) }).Should(Succeed())
+	g.Expect(dialWith(nil)).To(HaveOccurred())
+}
+
+func TestTLSCredentialsManagerCloneSharesState(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "tls-clone")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	certPath := filepath.Join(tempDir, "server.crt")
+	keyPath := filepath.Join(tempDir, "server.key")
+	writeKeyPair(t, certPath, keyPath, "original")
+
+	mgr, err := NewTLSCredentialsManager(TLSConfig{
+		Enabled:    true,
+		CertFile:   certPath,
+		KeyFile:    keyPath,
+		MinVersion: tls.VersionTLS12,
+	})
+	assert.NoError(t, err)
+	defer mgr.Stop()
+
+	clone := mgr.Clone()
+	assert.Same(t, mgr, clone, "Clone must share the same underlying manager so hot reloads remain visible")
+}
@@ -0,0 +1,78 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package container
+
+import "github.com/pkg/errors"
+
+// ContainerdLimits is the containerd-specific translation of ResourceLimits:
+// cgroups v2 unified limits plus the log rotation annotations consumed by
+// containerd's CRI log rotation.
+type ContainerdLimits struct {
+	// CgroupsMemoryMax is the cgroups v2 "memory.max" value, in bytes.
+	CgroupsMemoryMax int64
+	// CgroupsCPUWeight is the cgroups v2 "cpu.weight" value (1-10000),
+	// derived from the Docker-style CPUShares (2-262144) the peer is
+	// configured with.
+	CgroupsCPUWeight int64
+	// LogRotationMaxSize and LogRotationMaxFile mirror Docker's log-opt
+	// equivalents, applied by containerd's log rotation annotations
+	// (io.containerd.runtime.v2/log-rotation.*).
+	LogRotationMaxSize string
+	LogRotationMaxFile string
+	// NetworkNamespaceMode is containerd's equivalent of Docker's
+	// NetworkMode (e.g. "host", "none", "bridge").
+	NetworkNamespaceMode string
+}
+
+// ContainerdRuntime is the ContainerRuntime backend that launches chaincode
+// containers directly against a containerd socket, bypassing the Docker
+// daemon.
+type ContainerdRuntime struct{}
+
+// Name implements ContainerRuntime.
+func (c *ContainerdRuntime) Name() string { return "containerd" }
+
+// Validate implements ContainerRuntime.
+func (c *ContainerdRuntime) Validate(limits ResourceLimits) error {
+	if limits.CPUShares < 0 {
+		return errors.Errorf("containerd runtime: CPUShares must not be negative, got %d", limits.CPUShares)
+	}
+	return nil
+}
+
+// HostConfig implements ContainerRuntime, translating limits into cgroups v2
+// primitives and containerd's log rotation annotations.
+func (c *ContainerdRuntime) HostConfig(limits ResourceLimits) (interface{}, error) {
+	if err := c.Validate(limits); err != nil {
+		return nil, err
+	}
+	return &ContainerdLimits{
+		CgroupsMemoryMax:     limits.Memory,
+		CgroupsCPUWeight:     dockerSharesToCgroupsWeight(limits.CPUShares),
+		LogRotationMaxSize:   limits.LogMaxSize,
+		LogRotationMaxFile:   limits.LogMaxFile,
+		NetworkNamespaceMode: limits.NetworkMode,
+	}, nil
+}
+
+// dockerSharesToCgroupsWeight rescales Docker's CPUShares range (2-262144,
+// default 1024) onto the cgroups v2 cpu.weight range (1-10000, default 100),
+// the same linear mapping the OCI runtime spec documents for cgroups v1/v2
+// interop.
+func dockerSharesToCgroupsWeight(cpuShares int64) int64 {
+	if cpuShares <= 0 {
+		return 100
+	}
+	weight := 1 + ((cpuShares-2)*9999)/262142
+	if weight < 1 {
+		weight = 1
+	}
+	if weight > 10000 {
+		weight = 10000
+	}
+	return weight
+}
@@ -0,0 +1,42 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package container
+
+import (
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// DockerRuntime is the ContainerRuntime backend that launches chaincode
+// containers via the Docker daemon. It is the peer's original, and still
+// default, behavior.
+type DockerRuntime struct{}
+
+// Name implements ContainerRuntime.
+func (d *DockerRuntime) Name() string { return "docker" }
+
+// Validate implements ContainerRuntime. Docker places no additional
+// restrictions on the requested limits.
+func (d *DockerRuntime) Validate(limits ResourceLimits) error {
+	return nil
+}
+
+// HostConfig implements ContainerRuntime, translating limits directly into a
+// go-dockerclient HostConfig.
+func (d *DockerRuntime) HostConfig(limits ResourceLimits) (interface{}, error) {
+	return &docker.HostConfig{
+		NetworkMode: limits.NetworkMode,
+		Memory:      limits.Memory,
+		CPUShares:   limits.CPUShares,
+		LogConfig: docker.LogConfig{
+			Type: "json-file",
+			Config: map[string]string{
+				"max-size": limits.LogMaxSize,
+				"max-file": limits.LogMaxFile,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package container
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// PodResourceConfig is the Kubernetes-specific translation of
+// ResourceLimits, used to populate the chaincode Job/Pod's container spec.
+type PodResourceConfig struct {
+	// MemoryLimit and MemoryRequest are Kubernetes quantity strings (e.g.
+	// "2Gi"), both set to the peer's configured vm.docker.hostConfig.Memory.
+	MemoryLimit   string
+	MemoryRequest string
+	// CPURequest is a Kubernetes quantity string derived from the
+	// Docker-style CPUShares the peer is configured with.
+	CPURequest string
+	// LogRotationSidecar configures the sidecar container that tails and
+	// rotates the chaincode container's logs, since Kubernetes has no
+	// per-container log-driver option of its own.
+	LogRotationSidecar LogRotationSidecarConfig
+}
+
+// LogRotationSidecarConfig configures the log-rotation sidecar container
+// injected into the chaincode Pod to emulate Docker's json-file log driver
+// rotation (max-size, max-file).
+type LogRotationSidecarConfig struct {
+	MaxSize string
+	MaxFile string
+}
+
+// KubernetesRuntime is the ContainerRuntime backend that launches chaincode
+// containers as Kubernetes Jobs/Pods instead of directly managing a
+// container engine.
+type KubernetesRuntime struct{}
+
+// Name implements ContainerRuntime.
+func (k *KubernetesRuntime) Name() string { return "kubernetes" }
+
+// Validate implements ContainerRuntime. Kubernetes has no equivalent of
+// Docker's host network mode for chaincode Pods, since that would place the
+// chaincode container on the node's network namespace.
+func (k *KubernetesRuntime) Validate(limits ResourceLimits) error {
+	if limits.NetworkMode == "host" {
+		return errors.New("kubernetes runtime: vm.docker.hostConfig.NetworkMode=host is not supported, chaincode Pods always run in their own network namespace")
+	}
+	return nil
+}
+
+// HostConfig implements ContainerRuntime, translating limits into Pod
+// resource requests/limits and a log rotation sidecar configuration.
+func (k *KubernetesRuntime) HostConfig(limits ResourceLimits) (interface{}, error) {
+	if err := k.Validate(limits); err != nil {
+		return nil, err
+	}
+	return &PodResourceConfig{
+		MemoryLimit:   bytesToK8sQuantity(limits.Memory),
+		MemoryRequest: bytesToK8sQuantity(limits.Memory),
+		CPURequest:    cpuSharesToK8sQuantity(limits.CPUShares),
+		LogRotationSidecar: LogRotationSidecarConfig{
+			MaxSize: limits.LogMaxSize,
+			MaxFile: limits.LogMaxFile,
+		},
+	}, nil
+}
+
+// bytesToK8sQuantity renders a byte count as a Kubernetes quantity string
+// using the largest binary suffix that divides it evenly, falling back to
+// plain bytes.
+func bytesToK8sQuantity(bytes int64) string {
+	if bytes <= 0 {
+		return "0"
+	}
+	const unit = 1024
+	suffixes := []string{"Ki", "Mi", "Gi", "Ti"}
+	value := bytes
+	suffixIdx := -1
+	for i := range suffixes {
+		if value%unit != 0 {
+			break
+		}
+		value /= unit
+		suffixIdx = i
+	}
+	if suffixIdx == -1 {
+		return strconv.FormatInt(bytes, 10)
+	}
+	return strconv.FormatInt(value, 10) + suffixes[suffixIdx]
+}
+
+// cpuSharesToK8sQuantity converts Docker-style CPUShares (relative to the
+// default of 1024 representing one vCPU) into Kubernetes millicpu units.
+func cpuSharesToK8sQuantity(cpuShares int64) string {
+	if cpuShares <= 0 {
+		return "100m"
+	}
+	millicpu := (cpuShares * 1000) / 1024
+	if millicpu < 1 {
+		millicpu = 1
+	}
+	return strconv.FormatInt(millicpu, 10) + "m"
+}
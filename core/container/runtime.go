@@ -0,0 +1,55 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package container
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ResourceLimits is the runtime-agnostic description of the resource and
+// logging configuration requested for a chaincode container, as read from
+// the peer's vm.* configuration. Each ContainerRuntime backend translates
+// these into its own primitives.
+type ResourceLimits struct {
+	NetworkMode string
+	LogMaxSize  string
+	LogMaxFile  string
+	Memory      int64
+	CPUShares   int64
+}
+
+// ContainerRuntime launches and configures chaincode containers for a
+// specific backend (Docker, containerd, Kubernetes, ...), selected by
+// vm.type in the peer's configuration.
+type ContainerRuntime interface {
+	// Name returns the vm.type value this backend is selected by.
+	Name() string
+
+	// Validate returns an error if limits cannot be satisfied by this
+	// backend, e.g. a NetworkMode that has no Kubernetes equivalent.
+	Validate(limits ResourceLimits) error
+
+	// HostConfig translates limits into the backend-specific configuration
+	// used to launch a chaincode container.
+	HostConfig(limits ResourceLimits) (interface{}, error)
+}
+
+// NewContainerRuntime selects the ContainerRuntime implementation named by
+// vm.type ("docker", "containerd", or "kubernetes"), defaulting to "docker"
+// to preserve the peer's historical behavior when vm.type is unset.
+func NewContainerRuntime(vmType string) (ContainerRuntime, error) {
+	switch vmType {
+	case "", "docker":
+		return &DockerRuntime{}, nil
+	case "containerd":
+		return &ContainerdRuntime{}, nil
+	case "kubernetes":
+		return &KubernetesRuntime{}, nil
+	default:
+		return nil, errors.Errorf("unknown vm.type %q", vmType)
+	}
+}
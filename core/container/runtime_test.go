@@ -0,0 +1,103 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package container
+
+import (
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewContainerRuntime(t *testing.T) {
+	for _, vmType := range []string{"", "docker"} {
+		rt, err := NewContainerRuntime(vmType)
+		assert.NoError(t, err)
+		assert.Equal(t, "docker", rt.Name())
+	}
+
+	rt, err := NewContainerRuntime("containerd")
+	assert.NoError(t, err)
+	assert.Equal(t, "containerd", rt.Name())
+
+	rt, err = NewContainerRuntime("kubernetes")
+	assert.NoError(t, err)
+	assert.Equal(t, "kubernetes", rt.Name())
+
+	_, err = NewContainerRuntime("podman")
+	assert.Error(t, err)
+}
+
+func TestContainerRuntimeHostConfig(t *testing.T) {
+	limits := ResourceLimits{
+		NetworkMode: "bridge",
+		LogMaxSize:  "50m",
+		LogMaxFile:  "5",
+		Memory:      1024 * 1024 * 1024 * 2,
+		CPUShares:   512,
+	}
+
+	tests := []struct {
+		vmType string
+		verify func(t *testing.T, hostConfig interface{})
+	}{
+		{
+			vmType: "docker",
+			verify: func(t *testing.T, hostConfig interface{}) {
+				dockerConfig, ok := hostConfig.(*docker.HostConfig)
+				assert.True(t, ok)
+				assert.Equal(t, "bridge", dockerConfig.NetworkMode)
+				assert.Equal(t, "json-file", dockerConfig.LogConfig.Type)
+				assert.Equal(t, "50m", dockerConfig.LogConfig.Config["max-size"])
+				assert.Equal(t, "5", dockerConfig.LogConfig.Config["max-file"])
+				assert.Equal(t, limits.Memory, dockerConfig.Memory)
+				assert.Equal(t, int64(512), dockerConfig.CPUShares)
+			},
+		},
+		{
+			vmType: "containerd",
+			verify: func(t *testing.T, hostConfig interface{}) {
+				cfg, ok := hostConfig.(*ContainerdLimits)
+				assert.True(t, ok)
+				assert.Equal(t, limits.Memory, cfg.CgroupsMemoryMax)
+				assert.Equal(t, "bridge", cfg.NetworkNamespaceMode)
+				assert.Equal(t, "50m", cfg.LogRotationMaxSize)
+				assert.Equal(t, "5", cfg.LogRotationMaxFile)
+				assert.True(t, cfg.CgroupsCPUWeight >= 1 && cfg.CgroupsCPUWeight <= 10000)
+			},
+		},
+		{
+			vmType: "kubernetes",
+			verify: func(t *testing.T, hostConfig interface{}) {
+				cfg, ok := hostConfig.(*PodResourceConfig)
+				assert.True(t, ok)
+				assert.Equal(t, "2Gi", cfg.MemoryLimit)
+				assert.Equal(t, "500m", cfg.CPURequest)
+				assert.Equal(t, "50m", cfg.LogRotationSidecar.MaxSize)
+				assert.Equal(t, "5", cfg.LogRotationSidecar.MaxFile)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.vmType, func(t *testing.T) {
+			rt, err := NewContainerRuntime(tt.vmType)
+			assert.NoError(t, err)
+			hostConfig, err := rt.HostConfig(limits)
+			assert.NoError(t, err)
+			tt.verify(t, hostConfig)
+		})
+	}
+}
+
+func TestKubernetesRuntimeRejectsHostNetworkMode(t *testing.T) {
+	rt, err := NewContainerRuntime("kubernetes")
+	assert.NoError(t, err)
+
+	_, err = rt.HostConfig(ResourceLimits{NetworkMode: "host"})
+	assert.EqualError(t, err, "kubernetes runtime: vm.docker.hostConfig.NetworkMode=host is not supported, chaincode Pods always run in their own network namespace")
+}
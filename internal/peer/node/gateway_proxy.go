@@ -0,0 +1,208 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"plugin"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/hyperledger/fabric/common/viperutil"
+	"github.com/hyperledger/fabric/core/handlers/library"
+	peerproto "github.com/hyperledger/fabric/protos/peer"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/tmc/grpc-websocket-proxy/wsproxy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// defaultGatewayMaxResponseBuffer is the WebSocket response buffer size used
+// by the gateway when peer.gatewayMaxResponseBuffer is unset or not positive;
+// an explicitly configured positive value is used as given. It is
+// sized well above the 64 KiB default of the underlying proxy library so
+// that full block payloads delivered over the Deliver service are not
+// truncated before reaching browser/HTTP subscribers.
+const defaultGatewayMaxResponseBuffer = 10 * 1024 * 1024 // 10 MiB
+
+// gatewayDialTimeout bounds how long startGatewayServer waits to establish
+// the gRPC connection it registers handlers against, so a misconfigured or
+// unreachable peer listener (e.g. an mTLS-only listener dialed without
+// matching credentials) fails serve() instead of hanging it forever.
+const gatewayDialTimeout = 10 * time.Second
+
+// gatewayConfig captures the peer.gateway* settings that control the
+// in-process WebSocket/HTTP gateway started alongside the peer's gRPC
+// listener.
+type gatewayConfig struct {
+	ListenAddress     string
+	MaxResponseBuffer int
+	AllowedOrigins    []string
+	AuthFilters       library.Config
+}
+
+func gatewayConfigFromViper(v *viper.Viper) (gatewayConfig, error) {
+	cfg := gatewayConfig{
+		ListenAddress:     v.GetString("peer.gatewayListenAddress"),
+		MaxResponseBuffer: v.GetInt("peer.gatewayMaxResponseBuffer"),
+		AllowedOrigins:    v.GetStringSlice("peer.gatewayAllowedOrigins"),
+	}
+	if cfg.MaxResponseBuffer <= 0 {
+		cfg.MaxResponseBuffer = defaultGatewayMaxResponseBuffer
+	}
+	if err := viperutil.EnhancedExactUnmarshalKey("peer.handlers", &cfg.AuthFilters); err != nil {
+		return gatewayConfig{}, errors.WithMessage(err, "failed to unmarshal peer.handlers for gateway auth filters")
+	}
+	return cfg, nil
+}
+
+// GatewayServer runs a grpc-gateway JSON/REST transcoder wrapped in a
+// grpc-websocket-proxy, exposing the peer's Endorser, Deliver, and Admin gRPC
+// services to browser and plain HTTP clients on a single HTTP listener.
+type GatewayServer struct {
+	httpServer *http.Server
+	listener   net.Listener
+	conn       *grpc.ClientConn
+}
+
+// startGatewayServer dials peerGRPCAddress (the peer's own gRPC listener)
+// using creds - the same TLSCredentialsManager securing that listener, so an
+// mTLS-enabled peer and the gateway compose correctly - and starts serving
+// HTTP/WebSocket traffic on cfg.ListenAddress, transcoding to gRPC and
+// forwarding bidirectional streams such as Deliver over WebSockets.
+func startGatewayServer(cfg gatewayConfig, peerGRPCAddress string, creds credentials.TransportCredentials) (*GatewayServer, error) {
+	if cfg.ListenAddress == "" {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gatewayDialTimeout)
+	defer cancel()
+
+	mux := runtime.NewServeMux()
+
+	conn, err := grpc.DialContext(ctx, peerGRPCAddress, grpc.WithTransportCredentials(creds), grpc.WithBlock())
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to dial peer gRPC endpoint for gateway registration")
+	}
+
+	for _, register := range gatewayServiceRegistrars {
+		if err := register(ctx, mux, conn); err != nil {
+			conn.Close()
+			return nil, errors.WithMessage(err, "failed to register gateway service")
+		}
+	}
+
+	handler, err := withAuthFilters(withOriginAllowList(mux, cfg.AllowedOrigins), cfg.AuthFilters)
+	if err != nil {
+		conn.Close()
+		return nil, errors.WithMessage(err, "failed to load gateway auth filters")
+	}
+
+	listener, err := net.Listen("tcp", cfg.ListenAddress)
+	if err != nil {
+		conn.Close()
+		return nil, errors.WithMessage(err, "failed to listen on peer.gatewayListenAddress")
+	}
+
+	httpServer := &http.Server{
+		Handler: wsproxy.WebsocketProxy(handler, wsproxy.WithMaxRespBodyBufferSize(cfg.MaxResponseBuffer)),
+	}
+	go httpServer.Serve(listener)
+
+	logger.Infof("Starting peer HTTP/WebSocket gateway on %s", cfg.ListenAddress)
+
+	return &GatewayServer{httpServer: httpServer, listener: listener, conn: conn}, nil
+}
+
+// Stop gracefully shuts down the gateway's HTTP listener and closes the gRPC
+// connection opened to register its backing services.
+func (g *GatewayServer) Stop() {
+	if g == nil {
+		return
+	}
+	if g.httpServer != nil {
+		g.httpServer.Shutdown(context.Background())
+	}
+	if g.conn != nil {
+		g.conn.Close()
+	}
+}
+
+// gatewayServiceRegistrars lists the grpc-gateway handler registration
+// functions for each service the gateway exposes. Each entry is a
+// `RegisterXxxHandler` function generated from the service's .proto file.
+var gatewayServiceRegistrars = []func(context.Context, *runtime.ServeMux, *grpc.ClientConn) error{
+	peerproto.RegisterEndorserHandler,
+	peerproto.RegisterDeliverHandler,
+	peerproto.RegisterAdminHandler,
+}
+
+// withOriginAllowList rejects WebSocket/HTTP requests whose Origin header is
+// not present in allowedOrigins. An empty allow-list disables the check.
+func withOriginAllowList(next http.Handler, allowedOrigins []string) http.Handler {
+	if len(allowedOrigins) == 0 {
+		return next
+	}
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, o := range allowedOrigins {
+		allowed[o] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && !allowed[origin] {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AuthFilter is the interface an auth filter plugin must expose to wrap the
+// gateway's handler chain, mirroring the role the same plugin's symbol plays
+// in the peer's gRPC interceptor chain.
+type AuthFilter interface {
+	Apply(http.Handler) http.Handler
+}
+
+// withAuthFilters threads the gateway's HTTP handler through the same
+// per-method authentication filter plugins configured for the peer's gRPC
+// interceptor chain via peer.handlers.authFilters, so the two transports
+// enforce identical access control. Filters are applied in configured order,
+// with filters[0] wrapping (and therefore running before) filters[1], and so
+// on.
+func withAuthFilters(next http.Handler, filters library.Config) (http.Handler, error) {
+	handler := next
+	for i := len(filters.AuthFilters) - 1; i >= 0; i-- {
+		filter, err := loadAuthFilterPlugin(filters.AuthFilters[i])
+		if err != nil {
+			return nil, err
+		}
+		handler = filter.Apply(handler)
+	}
+	return handler, nil
+}
+
+// loadAuthFilterPlugin loads the shared object named by cfg.Library and
+// looks up its exported `NewFilter` constructor, the same convention the
+// peer's gRPC-side handler library uses to load auth filter plugins.
+func loadAuthFilterPlugin(cfg library.AuthFilterConfig) (AuthFilter, error) {
+	p, err := plugin.Open(cfg.Library)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to load auth filter plugin %s", cfg.Name)
+	}
+	sym, err := p.Lookup("NewFilter")
+	if err != nil {
+		return nil, errors.WithMessagef(err, "auth filter plugin %s has no NewFilter symbol", cfg.Name)
+	}
+	constructor, ok := sym.(func() AuthFilter)
+	if !ok {
+		return nil, errors.Errorf("auth filter plugin %s: NewFilter has an unexpected signature", cfg.Name)
+	}
+	return constructor(), nil
+}
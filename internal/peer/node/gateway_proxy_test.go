@@ -0,0 +1,152 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperledger/fabric/core/handlers/library"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/tmc/grpc-websocket-proxy/wsproxy"
+)
+
+func TestGatewayConfigFromViper(t *testing.T) {
+	defer viper.Reset()
+
+	config := `
+  peer:
+    gatewayListenAddress: 0.0.0.0:7081
+    gatewayAllowedOrigins:
+      - https://console.example.com
+    handlers:
+      authFilters:
+        -
+          name: filter1
+          library: /opt/lib/filter1.so
+  `
+	viper.SetConfigType("yaml")
+	assert.NoError(t, viper.ReadConfig(bytes.NewBuffer([]byte(config))))
+
+	cfg, err := gatewayConfigFromViper(viper.GetViper())
+	assert.NoError(t, err)
+	assert.Equal(t, "0.0.0.0:7081", cfg.ListenAddress)
+	assert.Equal(t, []string{"https://console.example.com"}, cfg.AllowedOrigins)
+	assert.Equal(t, defaultGatewayMaxResponseBuffer, cfg.MaxResponseBuffer)
+	assert.Len(t, cfg.AuthFilters.AuthFilters, 1)
+	assert.Equal(t, "filter1", cfg.AuthFilters.AuthFilters[0].Name)
+}
+
+func TestGatewayConfigFromViperHonorsExplicitBufferSize(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("peer.gatewayMaxResponseBuffer", 1024)
+
+	cfg, err := gatewayConfigFromViper(viper.GetViper())
+	assert.NoError(t, err)
+	assert.Equal(t, 1024, cfg.MaxResponseBuffer)
+}
+
+func TestWithOriginAllowList(t *testing.T) {
+	handler := withOriginAllowList(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), []string{"https://console.example.com"})
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+
+	req.Header.Set("Origin", "https://console.example.com")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	req.Header.Set("Origin", "https://evil.example.com")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp.Body.Close()
+}
+
+// TestGatewayProxyForwardsMultiMegabyteBlockOverWebSocket exercises the same
+// wsproxy.WebsocketProxy wiring startGatewayServer uses, against a backend
+// handler that writes a payload far larger than the proxy library's 64 KiB
+// default response buffer (modeling a large Deliver block event), and
+// asserts the WebSocket client receives it whole rather than truncated.
+func TestGatewayProxyForwardsMultiMegabyteBlockOverWebSocket(t *testing.T) {
+	const payloadSize = 12 * 1024 * 1024 // larger than the 64 KiB default buffer
+
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("a"), payloadSize))
+	})
+
+	handler := wsproxy.WebsocketProxy(backend, wsproxy.WithMaxRespBodyBufferSize(defaultGatewayMaxResponseBuffer))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("{}")))
+
+	_, data, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, payloadSize, len(data), "large block payload must not be truncated by the gateway's response buffer")
+}
+
+func TestWithAuthFiltersNoFiltersIsPassthrough(t *testing.T) {
+	called := false
+	handler, err := withAuthFilters(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}), library.Config{})
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.True(t, called)
+}
+
+func TestWithAuthFiltersPropagatesPluginLoadError(t *testing.T) {
+	_, err := withAuthFilters(http.NotFoundHandler(), library.Config{
+		AuthFilters: []library.AuthFilterConfig{{Name: "missing", Library: "/nonexistent/filter.so"}},
+	})
+	assert.Error(t, err)
+}
+
+func TestWithOriginAllowListEmptyAllowsAll(t *testing.T) {
+	called := false
+	handler := withOriginAllowList(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}), nil)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	req.Header.Set("Origin", "https://anything.example.com")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+	assert.True(t, called)
+}
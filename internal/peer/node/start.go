@@ -0,0 +1,279 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/comm"
+	"github.com/hyperledger/fabric/core/container"
+	"github.com/hyperledger/fabric/core/peer"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+var logger = flogging.MustGetLogger("nodeCmd")
+
+// startCmd returns the cobra command for "peer node start".
+func startCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "start",
+		Short: "Starts the peer node.",
+		Long:  `Starts a peer node that interacts with the network.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serve(args)
+		},
+	}
+}
+
+// serve wires up the peer's listeners and starts the gRPC servers. It is the
+// single entry point invoked by startCmd once configuration has been loaded,
+// and it keeps the servers running until the process receives SIGINT or
+// SIGTERM.
+func serve(args []string) error {
+	coreConfig, err := peer.GlobalConfig()
+	if err != nil {
+		return err
+	}
+
+	tlsCredentialsManager, err := comm.NewTLSCredentialsManager(comm.TLSConfigFromViper(viper.GetViper()))
+	if err != nil {
+		return errors.WithMessage(err, "failed to initialize peer TLS credentials")
+	}
+	defer tlsCredentialsManager.Stop()
+
+	peerListenAddress := viper.GetString("peer.listenAddress")
+	peerServer, _, err := startTLSGRPCServer(peerListenAddress, tlsCredentialsManager)
+	if err != nil {
+		return errors.WithMessage(err, "failed to start peer gRPC server")
+	}
+	if peerServer == nil {
+		return errors.New("peer.listenAddress must be set")
+	}
+	defer peerServer.GracefulStop()
+
+	peerHost, _, err := net.SplitHostPort(peerListenAddress)
+	if err != nil {
+		return errors.Errorf("peer.listenAddress is not in the proper host:port format: %s", err)
+	}
+
+	ccEndpoint, err := computeChaincodeEndpoint(coreConfig, peerHost)
+	if err != nil && viper.GetString("chaincode.mode") != "dev" {
+		return err
+	}
+	logger.Infof("Starting peer with chaincode endpoint: %s", ccEndpoint)
+
+	vmRuntime, err := container.NewContainerRuntime(viper.GetString("vm.type"))
+	if err != nil {
+		return errors.WithMessage(err, "failed to select container runtime")
+	}
+	if _, err := vmRuntime.HostConfig(vmResourceLimits()); err != nil {
+		return errors.WithMessage(err, "vm.docker.hostConfig is not valid for the configured vm.type")
+	}
+	containerRuntime = vmRuntime
+	logger.Infof("Launching chaincode containers via the %s runtime", vmRuntime.Name())
+
+	chaincodeListenAddress := viper.GetString("peer.chaincodeListenAddress")
+	chaincodeServer, _, err := startTLSGRPCServer(chaincodeListenAddress, tlsCredentialsManager)
+	if err != nil {
+		return errors.WithMessage(err, "failed to start chaincode support server")
+	}
+	if chaincodeServer != nil {
+		defer chaincodeServer.GracefulStop()
+		logger.Infof("Starting chaincode support server on %s", chaincodeListenAddress)
+	}
+
+	adminListenAddress := viper.GetString("peer.adminService.listenAddress")
+	if adminHasSeparateListener(peerListenAddress, adminListenAddress) {
+		adminServer, _, err := startTLSGRPCServer(adminListenAddress, tlsCredentialsManager)
+		if err != nil {
+			return errors.WithMessage(err, "failed to start admin server")
+		}
+		defer adminServer.GracefulStop()
+		logger.Infof("Starting admin server on %s", adminListenAddress)
+	}
+
+	registerBuiltinSystemChaincodes()
+	sysCCs, err := systemChaincodesFromViper(viper.GetViper())
+	if err != nil {
+		return errors.WithMessage(err, "failed to load peer.systemChaincodes")
+	}
+	for _, sysCC := range sysCCs {
+		logger.Infof("Registering system chaincode %s (external=%t, cc=%t)", sysCC.Name, sysCC.InvokableExternal, sysCC.InvokableCC)
+	}
+	recordLoadedSystemChaincodes(sysCCs)
+
+	gwConfig, err := gatewayConfigFromViper(viper.GetViper())
+	if err != nil {
+		return err
+	}
+	gatewayServer, err := startGatewayServer(gwConfig, peerListenAddress, tlsCredentialsManager)
+	if err != nil {
+		return errors.WithMessage(err, "failed to start peer HTTP/WebSocket gateway")
+	}
+	defer gatewayServer.Stop()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigCh
+	logger.Infof("Received %s, stopping peer", sig)
+	return nil
+}
+
+// startTLSGRPCServer starts a gRPC server bound to address, secured with
+// creds, so the peer's gRPC listener, chaincode listener, and admin listener
+// all share the same mTLS/hot-reload behavior via a single
+// TLSCredentialsManager. An empty address is a no-op: it returns a nil
+// server and listener rather than an error, since not every listener is
+// always configured (e.g. the admin listener usually rides on the peer's
+// main server instead of binding its own address).
+func startTLSGRPCServer(address string, creds credentials.TransportCredentials) (*grpc.Server, net.Listener, error) {
+	if address == "" {
+		return nil, nil, nil
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "failed to listen on %s", address)
+	}
+
+	server := grpc.NewServer(grpc.Creds(creds))
+	go server.Serve(listener)
+
+	return server, listener, nil
+}
+
+// computeChaincodeEndpoint determines the endpoint that chaincode containers
+// should use to reach the peer, preferring an explicit chaincode address,
+// falling back to the chaincode listen address, and finally to the peer's
+// own address with the default chaincode port.
+func computeChaincodeEndpoint(coreConfig *peer.Config, peerHostname string) (ccEndpoint string, err error) {
+	newCCAddress := coreConfig.ChaincodeAddress
+	if newCCAddress != "" {
+		host, port, err := net.SplitHostPort(newCCAddress)
+		if err != nil {
+			return "", errors.Errorf("peer.chaincodeAddress is not in the proper host:port format: %s", err)
+		}
+		ccIP := net.ParseIP(host)
+		if ccIP != nil && ccIP.IsUnspecified() {
+			return "", errors.Errorf("peer.chaincodeAddress' IP cannot be %s", host)
+		}
+		return host + ":" + port, nil
+	}
+
+	newCCListenAddress := coreConfig.ChaincodeListenAddress
+	if newCCListenAddress != "" {
+		host, port, err := net.SplitHostPort(newCCListenAddress)
+		if err != nil {
+			return "", errors.Errorf("peer.chaincodeListenAddress is not in the proper host:port format: %s", err)
+		}
+		ccListenerIP := net.ParseIP(host)
+		if ccListenerIP != nil && ccListenerIP.IsUnspecified() {
+			if peerHostname == "" {
+				return "", errors.New("peer.chaincodeListenAddress is not valid and peer address is unset")
+			}
+			peerIP := net.ParseIP(peerHostname)
+			if peerIP != nil && peerIP.IsUnspecified() {
+				return "", errors.New("peer.chaincodeListenAddress port is wildcard and peer address is unset")
+			}
+			return peerHostname + ":" + port, nil
+		}
+		return host + ":" + port, nil
+	}
+
+	if peerHostname == "" {
+		return "", errors.New("peer.address isn't set, and peer.chaincodeListenAddress/peer.chaincodeAddress are not set")
+	}
+	peerIP := net.ParseIP(peerHostname)
+	if peerIP != nil && peerIP.IsUnspecified() {
+		return "", errors.Errorf("peer.address' IP cannot be %s", peerHostname)
+	}
+	return peerHostname + ":7052", nil
+}
+
+// adminHasSeparateListener returns true when the admin service is bound to an
+// address that differs from the peer's main listen address.
+func adminHasSeparateListener(peerListenAddr string, adminListenAddress string) bool {
+	if adminListenAddress == "" {
+		return false
+	}
+
+	_, peerPort, err := net.SplitHostPort(peerListenAddr)
+	if err != nil {
+		panic(fmt.Errorf("peer ListenAddress missing port: %s", err))
+	}
+	adminHost, adminPort, err := net.SplitHostPort(adminListenAddress)
+	if err != nil {
+		panic(fmt.Errorf("admin ListenAddress missing port: %s", err))
+	}
+
+	sameAddr := adminHost == "0.0.0.0" || adminHost == "127.0.0.1" || adminHost == ""
+	if sameAddr && adminPort == peerPort {
+		return false
+	}
+	return true
+}
+
+// vmResourceLimits reads the backend-agnostic container resource settings
+// out of vm.docker.hostConfig.*, regardless of which vm.type is ultimately
+// selected to run chaincode containers.
+func vmResourceLimits() container.ResourceLimits {
+	dockerKey := func(key string) string { return "vm.docker.hostConfig." + key }
+
+	networkMode := viper.GetString(dockerKey("NetworkMode"))
+	if networkMode == "" {
+		networkMode = "host"
+	}
+
+	return container.ResourceLimits{
+		NetworkMode: networkMode,
+		LogMaxSize:  viper.GetString(dockerKey("LogConfig.Config.max-size")),
+		LogMaxFile:  viper.GetString(dockerKey("LogConfig.Config.max-file")),
+		Memory:      int64(viper.GetInt(dockerKey("Memory"))),
+		CPUShares:   int64(viper.GetInt(dockerKey("CPUShares"))),
+	}
+}
+
+// containerRuntime is the ContainerRuntime selected by vm.type. serve() sets
+// it to the runtime it resolved via container.NewContainerRuntime before
+// getDockerHostConfig is ever called, so the backend actually used to launch
+// chaincode containers matches the one vm.type configured rather than always
+// being Docker. It defaults to DockerRuntime, matching the peer's historical
+// behavior when serve() hasn't run yet (e.g. in tests that call
+// getDockerHostConfig directly).
+var containerRuntime container.ContainerRuntime = &container.DockerRuntime{}
+
+// getDockerHostConfig translates the peer's vm.docker.hostConfig settings
+// into a go-dockerclient HostConfig used to launch chaincode containers,
+// via whichever backend containerRuntime currently selects. It panics if
+// that backend isn't Docker, since only the Docker backend produces a
+// *docker.HostConfig; callers on other backends must consult containerRuntime
+// directly instead.
+func getDockerHostConfig() *docker.HostConfig {
+	hostConfig, err := containerRuntime.HostConfig(vmResourceLimits())
+	if err != nil {
+		// DockerRuntime.Validate never rejects a configuration, so an error
+		// here comes from a non-Docker backend (e.g. Kubernetes rejecting
+		// NetworkMode=host), which cannot produce a *docker.HostConfig either.
+		panic(err)
+	}
+	dockerHostConfig, ok := hostConfig.(*docker.HostConfig)
+	if !ok {
+		panic(errors.Errorf("getDockerHostConfig called with vm.type=%q selected, which does not produce a docker HostConfig", containerRuntime.Name()))
+	}
+	return dockerHostConfig
+}
+
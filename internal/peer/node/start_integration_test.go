@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	msptesttools "github.com/hyperledger/fabric/msp/mgmt/testtools"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+)
+
+// TestStartCmdRegistersConfiguredSystemChaincodes drives the peer through
+// the same startCmd/serve path TestStartCmd exercises, with a
+// peer.systemChaincodes entry injecting a custom SCC and disabling a
+// built-in one, and confirms both are reflected in the system chaincode list
+// serve() loaded and recorded - not just in the systemChaincodesFromViper
+// unit tests.
+func TestStartCmdRegistersConfiguredSystemChaincodes(t *testing.T) {
+	defer viper.Reset()
+	defer func() { registeredSysCCs = nil }()
+	g := NewGomegaWithT(t)
+
+	tempDir, err := ioutil.TempDir("", "startcmd-syscc")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(tempDir)
+
+	viper.Set("peer.address", "localhost:6071")
+	viper.Set("peer.listenAddress", "0.0.0.0:6071")
+	viper.Set("peer.chaincodeListenAddress", "0.0.0.0:6072")
+	viper.Set("peer.fileSystemPath", tempDir)
+	viper.Set("chaincode.executetimeout", "30s")
+	viper.Set("chaincode.mode", "dev")
+	viper.Set("vm.endpoint", "unix:///var/run/docker.sock")
+
+	config := `
+  peer:
+    systemChaincodes:
+      -
+        name: mycc
+        library: /opt/lib/mycc.so
+        invokableExternal: true
+      -
+        name: escc
+        enabled: false
+  `
+	viper.SetConfigType("yaml")
+	g.Expect(viper.MergeConfig(bytes.NewBuffer([]byte(config)))).To(Succeed())
+
+	msptesttools.LoadMSPSetupForTesting()
+
+	go func() {
+		cmd := startCmd()
+		cmd.Execute()
+	}()
+
+	g.Eventually(func() []string {
+		var names []string
+		for _, d := range loadedSystemChaincodesSnapshot() {
+			names = append(names, d.Name)
+		}
+		return names
+	}).Should(ContainElement("mycc"))
+
+	names := func() []string {
+		var out []string
+		for _, d := range loadedSystemChaincodesSnapshot() {
+			out = append(out, d.Name)
+		}
+		return out
+	}()
+	g.Expect(names).To(ContainElement("qscc"))
+	g.Expect(names).NotTo(ContainElement("escc"))
+}
@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/hyperledger/fabric/common/viperutil"
+	"github.com/hyperledger/fabric/core/container"
 	"github.com/hyperledger/fabric/core/handlers/library"
 	"github.com/hyperledger/fabric/core/peer"
 	"github.com/hyperledger/fabric/core/testutil"
@@ -178,14 +179,41 @@ func TestComputeChaincodeEndpoint(t *testing.T) {
 	// This scenario will be the same to scenarios 3: set up chaincodeAddress only.
 }
 
+// TestGetDockerHostConfig drives getDockerHostConfig through the same
+// containerRuntime package variable serve() sets from vm.type, rather than
+// only exercising the Docker backend in isolation, so a vm.type selection
+// that doesn't produce a *docker.HostConfig is caught here too.
 func TestGetDockerHostConfig(t *testing.T) {
-	testutil.SetupTestConfig()
-	hostConfig := getDockerHostConfig()
-	assert.NotNil(t, hostConfig)
-	assert.Equal(t, "host", hostConfig.NetworkMode)
-	assert.Equal(t, "json-file", hostConfig.LogConfig.Type)
-	assert.Equal(t, "50m", hostConfig.LogConfig.Config["max-size"])
-	assert.Equal(t, "5", hostConfig.LogConfig.Config["max-file"])
-	assert.Equal(t, int64(1024*1024*1024*2), hostConfig.Memory)
-	assert.Equal(t, int64(0), hostConfig.CPUShares)
-}
\ No newline at end of file
+	defer func() { containerRuntime = &container.DockerRuntime{} }()
+
+	tests := []struct {
+		name        string
+		runtime     container.ContainerRuntime
+		expectPanic bool
+	}{
+		{name: "docker", runtime: &container.DockerRuntime{}},
+		{name: "containerd", runtime: &container.ContainerdRuntime{}, expectPanic: true},
+		{name: "kubernetes", runtime: &container.KubernetesRuntime{}, expectPanic: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.SetupTestConfig()
+			containerRuntime = tt.runtime
+
+			if tt.expectPanic {
+				assert.Panics(t, func() { getDockerHostConfig() })
+				return
+			}
+
+			hostConfig := getDockerHostConfig()
+			assert.NotNil(t, hostConfig)
+			assert.Equal(t, "host", hostConfig.NetworkMode)
+			assert.Equal(t, "json-file", hostConfig.LogConfig.Type)
+			assert.Equal(t, "50m", hostConfig.LogConfig.Config["max-size"])
+			assert.Equal(t, "5", hostConfig.LogConfig.Config["max-file"])
+			assert.Equal(t, int64(1024*1024*1024*2), hostConfig.Memory)
+			assert.Equal(t, int64(0), hostConfig.CPUShares)
+		})
+	}
+}
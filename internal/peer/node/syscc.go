@@ -0,0 +1,204 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/viperutil"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// SysCCDescriptor describes a system chaincode to be deployed when the peer
+// starts, whether built in (qscc, escc, lscc, cscc) or injected by an
+// operator as a shared-object path.
+type SysCCDescriptor struct {
+	// Name is the system chaincode's invocation name, e.g. "qscc".
+	Name string
+	// Path is the shared-object path to load the chaincode implementation
+	// from. Built-in system chaincodes leave this empty.
+	Path string
+	// InvokableExternal allows the chaincode to be invoked by client
+	// transaction proposals, as opposed to only by other chaincodes.
+	InvokableExternal bool
+	// InvokableCC allows other chaincodes to invoke this one via
+	// chaincode-to-chaincode invocation.
+	InvokableCC bool
+	// Enabled controls whether the system chaincode is registered at all.
+	// It defaults to true; set to false to disable a built-in SCC.
+	Enabled bool
+	// StartupTimeout overrides chaincode.startuptimeout for this system
+	// chaincode only. A zero value means the global timeout applies.
+	StartupTimeout time.Duration
+}
+
+var (
+	registeredSysCCsMutex sync.Mutex
+	registeredSysCCs      []SysCCDescriptor
+)
+
+// builtinSystemChaincodes are the peer's always-available system
+// chaincodes. They are registered by default so that a peer.systemChaincodes
+// entry naming one of them toggles real, already-registered behavior
+// instead of a no-op.
+var builtinSystemChaincodes = []SysCCDescriptor{
+	{Name: "lscc", Enabled: true, InvokableExternal: true, InvokableCC: true},
+	{Name: "cscc", Enabled: true, InvokableExternal: true, InvokableCC: true},
+	{Name: "escc", Enabled: true, InvokableCC: true},
+	{Name: "qscc", Enabled: true, InvokableExternal: true},
+}
+
+// RegisterSystemChaincode registers a system chaincode descriptor to be
+// deployed alongside the peer's built-in system chaincodes. It must be
+// called before startCmd().Execute(), since the registered descriptors are
+// read once during serve().
+func RegisterSystemChaincode(descriptor SysCCDescriptor) {
+	registeredSysCCsMutex.Lock()
+	defer registeredSysCCsMutex.Unlock()
+	registeredSysCCs = append(registeredSysCCs, descriptor)
+}
+
+// registerBuiltinSystemChaincodes registers the peer's built-in system
+// chaincodes, skipping any name a caller has already registered (e.g. a
+// custom chaincode shadowing a built-in name, or a prior call in the same
+// process). serve() calls this before consulting peer.systemChaincodes so
+// the config-driven enable/disable toggle always has something to act on.
+func registerBuiltinSystemChaincodes() {
+	registeredSysCCsMutex.Lock()
+	defer registeredSysCCsMutex.Unlock()
+
+	registered := make(map[string]bool, len(registeredSysCCs))
+	for _, d := range registeredSysCCs {
+		registered[d.Name] = true
+	}
+	for _, d := range builtinSystemChaincodes {
+		if !registered[d.Name] {
+			registeredSysCCs = append(registeredSysCCs, d)
+		}
+	}
+}
+
+// sysCCConfigEntry is the YAML/viper shape of a single peer.systemChaincodes
+// entry, mirroring how peer.handlers.authFilters entries are declared.
+type sysCCConfigEntry struct {
+	Name              string `mapstructure:"name"`
+	Library           string `mapstructure:"library"`
+	Enabled           *bool  `mapstructure:"enabled"`
+	InvokableExternal bool   `mapstructure:"invokableExternal"`
+	InvokableCC       bool   `mapstructure:"invokableCC"`
+	StartupTimeout    string `mapstructure:"startupTimeout"`
+}
+
+// loadSystemChaincodeConfig reads the peer.systemChaincodes list.
+// viperutil.EnhancedExactUnmarshalKey reads the process-wide viper instance,
+// so v is not consulted.
+func loadSystemChaincodeConfig(v *viper.Viper) ([]sysCCConfigEntry, error) {
+	var entries []sysCCConfigEntry
+	if err := viperutil.EnhancedExactUnmarshalKey("peer.systemChaincodes", &entries); err != nil {
+		return nil, errors.WithMessage(err, "failed to unmarshal peer.systemChaincodes")
+	}
+	return entries, nil
+}
+
+// systemChaincodesFromViper merges the descriptors registered in-process via
+// RegisterSystemChaincode with the peer.systemChaincodes entries (read from
+// the process-wide viper instance, as loadSystemChaincodeConfig does),
+// letting config entries toggle built-in SCCs on/off by name and override
+// their per-SCC chaincode startup timeout.
+func systemChaincodesFromViper(v *viper.Viper) ([]SysCCDescriptor, error) {
+	entries, err := loadSystemChaincodeConfig(v)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[string]sysCCConfigEntry, len(entries))
+	var customEntries []sysCCConfigEntry
+	for _, e := range entries {
+		if e.Name == "" {
+			return nil, errors.New("peer.systemChaincodes entry is missing a name")
+		}
+		overrides[e.Name] = e
+		if e.Library != "" {
+			customEntries = append(customEntries, e)
+		}
+	}
+
+	registeredSysCCsMutex.Lock()
+	builtins := make([]SysCCDescriptor, len(registeredSysCCs))
+	copy(builtins, registeredSysCCs)
+	registeredSysCCsMutex.Unlock()
+
+	descriptors := make([]SysCCDescriptor, 0, len(builtins)+len(customEntries))
+	for _, d := range builtins {
+		if override, ok := overrides[d.Name]; ok {
+			if override.Enabled != nil {
+				d.Enabled = *override.Enabled
+			}
+			if override.StartupTimeout != "" {
+				timeout, err := time.ParseDuration(override.StartupTimeout)
+				if err != nil {
+					return nil, errors.WithMessagef(err, "invalid startupTimeout for system chaincode %s", d.Name)
+				}
+				d.StartupTimeout = timeout
+			}
+		}
+		if !d.Enabled {
+			continue
+		}
+		descriptors = append(descriptors, d)
+	}
+
+	for _, e := range customEntries {
+		descriptor := SysCCDescriptor{
+			Name:              e.Name,
+			Path:              e.Library,
+			InvokableExternal: e.InvokableExternal,
+			InvokableCC:       e.InvokableCC,
+			Enabled:           e.Enabled == nil || *e.Enabled,
+		}
+		if e.StartupTimeout != "" {
+			timeout, err := time.ParseDuration(e.StartupTimeout)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "invalid startupTimeout for system chaincode %s", e.Name)
+			}
+			descriptor.StartupTimeout = timeout
+		}
+		if !descriptor.Enabled {
+			continue
+		}
+		descriptors = append(descriptors, descriptor)
+	}
+
+	return descriptors, nil
+}
+
+var (
+	lastLoadedSysCCsMutex sync.Mutex
+	lastLoadedSysCCs      []SysCCDescriptor
+)
+
+// recordLoadedSystemChaincodes is called by serve() after loading system
+// chaincodes so tests driving the peer through startCmd (the same path
+// TestStartCmd exercises) can observe the descriptor list serve() loaded,
+// rather than only unit-testing systemChaincodesFromViper in isolation.
+// serve() logs and records these descriptors; it does not deploy them to
+// the chaincode support server.
+func recordLoadedSystemChaincodes(descriptors []SysCCDescriptor) {
+	lastLoadedSysCCsMutex.Lock()
+	defer lastLoadedSysCCsMutex.Unlock()
+	lastLoadedSysCCs = descriptors
+}
+
+func loadedSystemChaincodesSnapshot() []SysCCDescriptor {
+	lastLoadedSysCCsMutex.Lock()
+	defer lastLoadedSysCCsMutex.Unlock()
+	out := make([]SysCCDescriptor, len(lastLoadedSysCCs))
+	copy(out, lastLoadedSysCCs)
+	return out
+}
@@ -0,0 +1,99 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterSystemChaincode(t *testing.T) {
+	defer func() { registeredSysCCs = nil }()
+
+	RegisterSystemChaincode(SysCCDescriptor{Name: "qscc", Enabled: true, InvokableExternal: true})
+	RegisterSystemChaincode(SysCCDescriptor{Name: "custom", Path: "/opt/lib/custom.so", Enabled: true, InvokableCC: true})
+
+	assert.Len(t, registeredSysCCs, 2)
+	assert.Equal(t, "qscc", registeredSysCCs[0].Name)
+	assert.Equal(t, "custom", registeredSysCCs[1].Name)
+}
+
+func TestSystemChaincodesFromViperDisablesBuiltin(t *testing.T) {
+	defer viper.Reset()
+	defer func() { registeredSysCCs = nil }()
+
+	RegisterSystemChaincode(SysCCDescriptor{Name: "qscc", Enabled: true, InvokableExternal: true})
+	RegisterSystemChaincode(SysCCDescriptor{Name: "escc", Enabled: true, InvokableCC: true})
+
+	config := `
+  peer:
+    systemChaincodes:
+      -
+        name: qscc
+        enabled: false
+  `
+	viper.SetConfigType("yaml")
+	assert.NoError(t, viper.ReadConfig(bytes.NewBuffer([]byte(config))))
+
+	descriptors, err := systemChaincodesFromViper(viper.GetViper())
+	assert.NoError(t, err)
+	assert.Len(t, descriptors, 1)
+	assert.Equal(t, "escc", descriptors[0].Name)
+}
+
+func TestSystemChaincodesFromViperInjectsCustomSCC(t *testing.T) {
+	defer viper.Reset()
+	defer func() { registeredSysCCs = nil }()
+
+	config := `
+  peer:
+    systemChaincodes:
+      -
+        name: mycc
+        library: /opt/lib/mycc.so
+        invokableExternal: true
+        invokableCC: false
+        startupTimeout: 45s
+  `
+	viper.SetConfigType("yaml")
+	assert.NoError(t, viper.ReadConfig(bytes.NewBuffer([]byte(config))))
+
+	descriptors, err := systemChaincodesFromViper(viper.GetViper())
+	assert.NoError(t, err)
+	assert.Len(t, descriptors, 1)
+	assert.Equal(t, "mycc", descriptors[0].Name)
+	assert.Equal(t, "/opt/lib/mycc.so", descriptors[0].Path)
+	assert.True(t, descriptors[0].InvokableExternal)
+	assert.False(t, descriptors[0].InvokableCC)
+	assert.Equal(t, 45*time.Second, descriptors[0].StartupTimeout)
+}
+
+func TestSystemChaincodesFromViperOverridesStartupTimeout(t *testing.T) {
+	defer viper.Reset()
+	defer func() { registeredSysCCs = nil }()
+
+	RegisterSystemChaincode(SysCCDescriptor{Name: "lscc", Enabled: true})
+
+	config := `
+  peer:
+    systemChaincodes:
+      -
+        name: lscc
+        startupTimeout: 2m
+  `
+	viper.SetConfigType("yaml")
+	assert.NoError(t, viper.ReadConfig(bytes.NewBuffer([]byte(config))))
+
+	descriptors, err := systemChaincodesFromViper(viper.GetViper())
+	assert.NoError(t, err)
+	assert.Len(t, descriptors, 1)
+	assert.Equal(t, 2*time.Minute, descriptors[0].StartupTimeout)
+}